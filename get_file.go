@@ -13,6 +13,12 @@ import (
 // a file scheme.
 type FileGetter struct {
 	next Getter
+
+	// PreferReflink, if true, makes Get clone the source tree with
+	// reflinks instead of symlinking it. This is useful for callers that
+	// must treat the destination as fully materialized data rather than
+	// a tree of links back into the source.
+	PreferReflink bool
 }
 
 func (g *FileGetter) Mode(ctx context.Context, u *url.URL) (Mode, error) {
@@ -57,16 +63,26 @@ func (g *FileGetter) Get(ctx context.Context, req *Request) error {
 		return nil
 	}
 
-	// If the destination already exists, it must be a symlink
+	// If the destination already exists, remove it so we can recreate it.
+	// Without PreferReflink it must be a symlink, otherwise Get fails.
+	// With PreferReflink, Get materializes a real directory tree rather
+	// than a symlink, so a pre-existing destination is a directory from a
+	// prior Get, not a symlink, and needs a recursive removal.
 	if err == nil {
-		mode := fi.Mode()
-		if mode&os.ModeSymlink == 0 {
-			return fmt.Errorf("destination exists and is not a symlink")
-		}
+		if g.PreferReflink {
+			if err := os.RemoveAll(req.Dst); err != nil {
+				return err
+			}
+		} else {
+			mode := fi.Mode()
+			if mode&os.ModeSymlink == 0 {
+				return fmt.Errorf("destination exists and is not a symlink")
+			}
 
-		// Remove the destination
-		if err := os.Remove(req.Dst); err != nil {
-			return err
+			// Remove the destination
+			if err := os.Remove(req.Dst); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -75,6 +91,10 @@ func (g *FileGetter) Get(ctx context.Context, req *Request) error {
 		return err
 	}
 
+	if g.PreferReflink {
+		return reflinkTree(ctx, path, req.Dst)
+	}
+
 	return SymlinkAny(path, req.Dst)
 }
 
@@ -134,6 +154,14 @@ func (g *FileGetter) GetFile(ctx context.Context, req *Request) error {
 		}
 	}
 
+	// Try a copy-on-write clone before falling back to a full byte copy.
+	// This is effectively free on filesystems like BTRFS, XFS, and APFS.
+	if err := reflink(path, req.Dst); err == nil {
+		return nil
+	} else if !reflinkFallback(err) {
+		return err
+	}
+
 	// Copy
 	srcF, err := os.Open(path)
 	if err != nil {
@@ -228,3 +256,43 @@ func (g *FileGetter) Next() Getter {
 func (g *FileGetter) SetNext(next Getter) {
 	g.next = next
 }
+
+// reflinkTree recursively clones src into dst, reflinking each regular
+// file and falling back to a byte copy for any file where the reflink
+// fast path isn't available.
+func reflinkTree(ctx context.Context, src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+
+		if err := reflink(path, target); err == nil || !reflinkFallback(err) {
+			return err
+		}
+
+		srcF, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer srcF.Close()
+
+		dstF, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer dstF.Close()
+
+		_, err = Copy(ctx, dstF, srcF)
+		return err
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 )
 
 // HgGetter is a Getter implementation that will download a module from
@@ -37,14 +39,25 @@ func (g *HgGetter) Get(ctx context.Context, req *Request) error {
 
 	// Extract some query parameters we use
 	var rev string
+	var bundle bool
 	q := newURL.Query()
 	if len(q) > 0 {
 		rev = q.Get("rev")
 		q.Del("rev")
 
+		bundle, _ = strconv.ParseBool(q.Get("bundle"))
+		q.Del("bundle")
+
 		newURL.RawQuery = q.Encode()
 	}
 
+	if bundle || g.isBundlePath(newURL.Path) {
+		// The source is a Mercurial changegroup bundle rather than a
+		// repository. Fetch and unbundle it instead of cloning, which
+		// is much faster when only a single revision is needed.
+		return g.getBundle(ctx, req.Dst, newURL, rev)
+	}
+
 	_, err = os.Stat(req.Dst)
 	if err != nil && !os.IsNotExist(err) {
 		return err
@@ -124,6 +137,61 @@ func (g *HgGetter) update(ctx context.Context, dst string, u *url.URL, rev strin
 	return getRunCommand(cmd)
 }
 
+// isBundlePath reports whether p looks like a Mercurial changegroup bundle
+// produced by `hg bundle`, rather than a repository path.
+func (g *HgGetter) isBundlePath(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".hg", ".bundle":
+		return true
+	default:
+		return false
+	}
+}
+
+// getBundle downloads a Mercurial changegroup bundle over HTTP and unbundles
+// it into dst, instead of cloning the full repository history. This mirrors
+// the git-bundle fast path and is much quicker for CI use cases that would
+// otherwise have to clone entire histories.
+func (g *HgGetter) getBundle(ctx context.Context, dst string, u *url.URL, rev string) error {
+	td, tdcloser, err := safetemp.Dir("", "getter")
+	if err != nil {
+		return err
+	}
+	defer tdcloser.Close()
+
+	bundlePath := filepath.Join(td, filepath.Base(u.Path))
+	hg := &HttpGetter{}
+	if err := hg.GetFile(ctx, &Request{u: u, Dst: bundlePath}); err != nil {
+		return fmt.Errorf("error downloading hg bundle: %s", err)
+	}
+
+	// Only initialize a fresh repository the first time; a re-Get against
+	// an already-unbundled destination should reuse it, just like the
+	// clone/pull path above reuses an existing checkout.
+	if _, err := os.Stat(filepath.Join(dst, ".hg")); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := os.MkdirAll(dst, 0755); err != nil {
+			return err
+		}
+
+		cmd := exec.CommandContext(ctx, "hg", "init", dst)
+		if err := getRunCommand(cmd); err != nil {
+			return fmt.Errorf("error initializing hg repository: %s", err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "hg", "unbundle", bundlePath)
+	cmd.Dir = dst
+	if err := getRunCommand(cmd); err != nil {
+		return fmt.Errorf("error unbundling hg bundle, it may be incompatible with the repository format: %s", err)
+	}
+
+	return g.update(ctx, dst, u, rev)
+}
+
 func (g *HgGetter) Detect(req *Request) (string, bool, error) {
 	src := req.Src
 	if len(src) == 0 {
@@ -6,7 +6,10 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -14,29 +17,54 @@ import (
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
+// defaultS3Concurrency is the number of objects that are downloaded
+// concurrently by S3Getter.Get when no explicit Concurrency is set.
+const defaultS3Concurrency = 10
+
 // S3Getter is a Getter implementation that will download a module from
 // a S3 bucket.
 type S3Getter struct {
+	// Concurrency controls how many objects are downloaded in parallel
+	// when fetching a directory. If zero, defaultS3Concurrency is used.
+	Concurrency int
+
+	// Client, if set, is used instead of building a client from a fresh
+	// session. This lets callers plug in a mock for tests or a session
+	// with custom retry/backoff configuration.
+	Client s3iface.S3API
+}
+
+// s3URL holds the result of parsing a go-getter S3 source URL.
+type s3URL struct {
+	host           string
+	scheme         string
+	region         string
+	bucket         string
+	path           string
+	version        string
+	creds          *credentials.Credentials
+	anonymous      bool
+	endpoint       string
+	forcePathStyle bool
 }
 
 func (g *S3Getter) Mode(ctx context.Context, u *url.URL) (Mode, error) {
 	// Parse URL
-	region, bucket, path, _, creds, err := g.parseUrl(u)
+	parsed, err := g.parseUrl(u)
 	if err != nil {
 		return 0, err
 	}
 
-	// Create client config
-	config := g.getAWSConfig(region, u, creds)
-	sess := session.New(config)
-	client := s3.New(sess)
+	client := g.getClient(parsed)
 
 	// List the object(s) at the given prefix
 	req := &s3.ListObjectsInput{
-		Bucket: aws.String(bucket),
-		Prefix: aws.String(path),
+		Bucket: aws.String(parsed.bucket),
+		Prefix: aws.String(parsed.path),
 	}
 	resp, err := client.ListObjects(req)
 	if err != nil {
@@ -45,12 +73,12 @@ func (g *S3Getter) Mode(ctx context.Context, u *url.URL) (Mode, error) {
 
 	for _, o := range resp.Contents {
 		// Use file mode on exact match.
-		if *o.Key == path {
+		if *o.Key == parsed.path {
 			return ModeFile, nil
 		}
 
 		// Use dir mode if child keys are found.
-		if strings.HasPrefix(*o.Key, path+"/") {
+		if strings.HasPrefix(*o.Key, parsed.path+"/") {
 			return ModeDir, nil
 		}
 	}
@@ -63,10 +91,11 @@ func (g *S3Getter) Mode(ctx context.Context, u *url.URL) (Mode, error) {
 func (g *S3Getter) Get(ctx context.Context, req *Request) error {
 
 	// Parse URL
-	region, bucket, path, _, creds, err := g.parseUrl(req.u)
+	parsed, err := g.parseUrl(req.u)
 	if err != nil {
 		return err
 	}
+	bucket, path := parsed.bucket, parsed.path
 
 	// Remove destination if it already exists
 	_, err = os.Stat(req.Dst)
@@ -86,68 +115,180 @@ func (g *S3Getter) Get(ctx context.Context, req *Request) error {
 		return err
 	}
 
-	config := g.getAWSConfig(region, req.u, creds)
-	sess := session.New(config)
-	client := s3.New(sess)
-
-	// List files in path, keep listing until no more objects are found
-	lastMarker := ""
-	hasMore := true
-	for hasMore {
-		s3Req := &s3.ListObjectsInput{
-			Bucket: aws.String(bucket),
-			Prefix: aws.String(path),
-		}
-		if lastMarker != "" {
-			s3Req.Marker = aws.String(lastMarker)
-		}
+	client := g.getClient(parsed)
 
-		resp, err := client.ListObjects(s3Req)
-		if err != nil {
-			return err
-		}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		hasMore = aws.BoolValue(resp.IsTruncated)
+	concurrency := g.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultS3Concurrency
+	}
 
-		// Get each object storing each file relative to the destination path
-		for _, object := range resp.Contents {
-			lastMarker = aws.StringValue(object.Key)
-			objPath := aws.StringValue(object.Key)
+	keys := make(chan string)
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for objPath := range keys {
+				objDst, err := filepath.Rel(path, objPath)
+				if err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+				objDst = filepath.Join(req.Dst, objDst)
+
+				if err := g.getObject(ctx, client, objDst, bucket, objPath, ""); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
 
-			// If the key ends with a backslash assume it is a directory and ignore
-			if strings.HasSuffix(objPath, "/") {
-				continue
+	// List files in path, keep paginating with ContinuationToken until no
+	// more objects are found, feeding keys to the worker pool as we go.
+	listErr := func() error {
+		continuationToken := ""
+		for {
+			s3Req := &s3.ListObjectsV2Input{
+				Bucket: aws.String(bucket),
+				Prefix: aws.String(path),
+			}
+			if continuationToken != "" {
+				s3Req.ContinuationToken = aws.String(continuationToken)
 			}
 
-			// Get the object destination path
-			objDst, err := filepath.Rel(path, objPath)
+			resp, err := client.ListObjectsV2WithContext(ctx, s3Req)
 			if err != nil {
 				return err
 			}
-			objDst = filepath.Join(req.Dst, objDst)
 
-			if err := g.getObject(ctx, client, objDst, bucket, objPath, ""); err != nil {
-				return err
+			for _, object := range resp.Contents {
+				objPath := aws.StringValue(object.Key)
+
+				// If the key ends with a slash assume it is a directory and ignore
+				if strings.HasSuffix(objPath, "/") {
+					continue
+				}
+
+				select {
+				case keys <- objPath:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			if !aws.BoolValue(resp.IsTruncated) {
+				return nil
 			}
+			continuationToken = aws.StringValue(resp.NextContinuationToken)
 		}
+	}()
+	close(keys)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
 	}
 
-	return nil
+	return listErr
 }
 
 func (g *S3Getter) GetFile(ctx context.Context, req *Request) error {
-	region, bucket, path, version, creds, err := g.parseUrl(req.u)
+	parsed, err := g.parseUrl(req.u)
 	if err != nil {
 		return err
 	}
 
-	config := g.getAWSConfig(region, req.u, creds)
+	client := g.getClient(parsed)
+
+	// Create all the parent directories
+	if err := os.MkdirAll(filepath.Dir(req.Dst), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(req.Dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Use the managed downloader so large objects are fetched as
+	// range-based, concurrent multipart downloads.
+	downloader := s3manager.NewDownloaderWithClient(client)
+	getReq := &s3.GetObjectInput{
+		Bucket: aws.String(parsed.bucket),
+		Key:    aws.String(parsed.path),
+	}
+	if parsed.version != "" {
+		getReq.VersionId = aws.String(parsed.version)
+	}
+
+	_, err = downloader.DownloadWithContext(ctx, f, getReq)
+	return err
+}
+
+// PresignGet returns a presigned URL that allows downloading the object at
+// u without sharing AWS credentials with the holder of the URL. This is
+// useful when go-getter is embedded in orchestration tools that need to
+// hand a short-lived download link to another process or machine.
+func (g *S3Getter) PresignGet(ctx context.Context, u *url.URL, expires time.Duration) (string, error) {
+	parsed, err := g.parseUrl(u)
+	if err != nil {
+		return "", err
+	}
+
+	client := g.getClient(parsed)
+	getReq := &s3.GetObjectInput{
+		Bucket: aws.String(parsed.bucket),
+		Key:    aws.String(parsed.path),
+	}
+	if parsed.version != "" {
+		getReq.VersionId = aws.String(parsed.version)
+	}
+
+	presignReq, _ := client.GetObjectRequest(getReq)
+	presignReq.SetContext(ctx)
+	return presignReq.Presign(expires)
+}
+
+// PresignPut returns a presigned URL that allows uploading an object to u
+// without sharing AWS credentials with the holder of the URL.
+func (g *S3Getter) PresignPut(ctx context.Context, u *url.URL, expires time.Duration) (string, error) {
+	parsed, err := g.parseUrl(u)
+	if err != nil {
+		return "", err
+	}
+
+	client := g.getClient(parsed)
+	putReq := &s3.PutObjectInput{
+		Bucket: aws.String(parsed.bucket),
+		Key:    aws.String(parsed.path),
+	}
+
+	presignReq, _ := client.PutObjectRequest(putReq)
+	presignReq.SetContext(ctx)
+	return presignReq.Presign(expires)
+}
+
+func (g *S3Getter) getClient(parsed s3URL) s3iface.S3API {
+	if g.Client != nil {
+		return g.Client
+	}
+
+	config := g.getAWSConfig(parsed)
 	sess := session.New(config)
-	client := s3.New(sess)
-	return g.getObject(ctx, client, req.Dst, bucket, path, version)
+	return s3.New(sess)
 }
 
-func (g *S3Getter) getObject(ctx context.Context, client *s3.S3, dst, bucket, key, version string) error {
+func (g *S3Getter) getObject(ctx context.Context, client s3iface.S3API, dst, bucket, key, version string) error {
 	req := &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
@@ -156,7 +297,7 @@ func (g *S3Getter) getObject(ctx context.Context, client *s3.S3, dst, bucket, ke
 		req.VersionId = aws.String(version)
 	}
 
-	resp, err := client.GetObject(req)
+	resp, err := client.GetObjectWithContext(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -176,9 +317,12 @@ func (g *S3Getter) getObject(ctx context.Context, client *s3.S3, dst, bucket, ke
 	return err
 }
 
-func (g *S3Getter) getAWSConfig(region string, url *url.URL, creds *credentials.Credentials) *aws.Config {
+func (g *S3Getter) getAWSConfig(parsed s3URL) *aws.Config {
 	conf := &aws.Config{}
-	if creds == nil {
+	creds := parsed.creds
+	if parsed.anonymous {
+		creds = credentials.AnonymousCredentials
+	} else if creds == nil {
 		// Grab the metadata URL
 		metadataURL := os.Getenv("AWS_METADATA_URL")
 		if metadataURL == "" {
@@ -197,23 +341,38 @@ func (g *S3Getter) getAWSConfig(region string, url *url.URL, creds *credentials.
 			})
 	}
 
-	if creds != nil {
-		conf.Endpoint = &url.Host
+	switch {
+	case parsed.endpoint != "":
+		// An explicit endpoint was given (e.g. a MinIO/Spaces/Ceph
+		// deployment), so use it instead of the URL host.
+		conf.Endpoint = aws.String(parsed.endpoint)
+		conf.S3ForcePathStyle = aws.Bool(parsed.forcePathStyle)
+		if parsed.scheme == "http" {
+			conf.DisableSSL = aws.Bool(true)
+		}
+	default:
+		// No explicit endpoint, so fall back to whatever host the URL
+		// pointed at. This is what lets callers shove an S3-compatible
+		// host straight into the URL without a separate query param.
+		conf.Endpoint = aws.String(parsed.host)
 		conf.S3ForcePathStyle = aws.Bool(true)
-		if url.Scheme == "http" {
+		if parsed.scheme == "http" {
 			conf.DisableSSL = aws.Bool(true)
 		}
 	}
 
 	conf.Credentials = creds
-	if region != "" {
-		conf.Region = aws.String(region)
+	if parsed.region != "" {
+		conf.Region = aws.String(parsed.region)
 	}
 
 	return conf
 }
 
-func (g *S3Getter) parseUrl(u *url.URL) (region, bucket, path, version string, creds *credentials.Credentials, err error) {
+func (g *S3Getter) parseUrl(u *url.URL) (result s3URL, err error) {
+	result.host = u.Host
+	result.scheme = u.Scheme
+
 	// This just check whether we are dealing with S3 or
 	// any other S3 compliant service. S3 has a predictable
 	// url as others do not
@@ -227,9 +386,9 @@ func (g *S3Getter) parseUrl(u *url.URL) (region, bucket, path, version string, c
 		}
 
 		// Parse the region out of the first part of the host
-		region = strings.TrimPrefix(strings.TrimPrefix(hostParts[0], "s3-"), "s3")
-		if region == "" {
-			region = "us-east-1"
+		result.region = strings.TrimPrefix(strings.TrimPrefix(hostParts[0], "s3-"), "s3")
+		if result.region == "" {
+			result.region = "us-east-1"
 		}
 
 		pathParts := strings.SplitN(u.Path, "/", 3)
@@ -238,9 +397,9 @@ func (g *S3Getter) parseUrl(u *url.URL) (region, bucket, path, version string, c
 			return
 		}
 
-		bucket = pathParts[1]
-		path = pathParts[2]
-		version = u.Query().Get("version")
+		result.bucket = pathParts[1]
+		result.path = pathParts[2]
+		result.version = u.Query().Get("version")
 
 	} else {
 		pathParts := strings.SplitN(u.Path, "/", 3)
@@ -248,23 +407,28 @@ func (g *S3Getter) parseUrl(u *url.URL) (region, bucket, path, version string, c
 			err = fmt.Errorf("URL is not a valid S3 complaint URL")
 			return
 		}
-		bucket = pathParts[1]
-		path = pathParts[2]
-		version = u.Query().Get("version")
-		region = u.Query().Get("region")
-		if region == "" {
-			region = "us-east-1"
+		result.bucket = pathParts[1]
+		result.path = pathParts[2]
+		result.version = u.Query().Get("version")
+		result.region = u.Query().Get("region")
+		if result.region == "" {
+			result.region = "us-east-1"
 		}
 	}
 
-	_, hasAwsId := u.Query()["aws_access_key_id"]
-	_, hasAwsSecret := u.Query()["aws_access_key_secret"]
-	_, hasAwsToken := u.Query()["aws_access_token"]
+	q := u.Query()
+	result.endpoint = q.Get("endpoint")
+	result.forcePathStyle, _ = strconv.ParseBool(q.Get("s3_force_path_style"))
+	result.anonymous, _ = strconv.ParseBool(q.Get("anonymous"))
+
+	_, hasAwsId := q["aws_access_key_id"]
+	_, hasAwsSecret := q["aws_access_key_secret"]
+	_, hasAwsToken := q["aws_access_token"]
 	if hasAwsId || hasAwsSecret || hasAwsToken {
-		creds = credentials.NewStaticCredentials(
-			u.Query().Get("aws_access_key_id"),
-			u.Query().Get("aws_access_key_secret"),
-			u.Query().Get("aws_access_token"),
+		result.creds = credentials.NewStaticCredentials(
+			q.Get("aws_access_key_id"),
+			q.Get("aws_access_key_secret"),
+			q.Get("aws_access_token"),
 		)
 	}
 
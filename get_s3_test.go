@@ -0,0 +1,81 @@
+package getter
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestS3Getter_getAWSConfig(t *testing.T) {
+	cases := []struct {
+		name               string
+		rawURL             string
+		wantEndpoint       string
+		wantForcePathStyle bool
+		wantDisableSSL     bool
+	}{
+		{
+			name:               "plain host, no query params",
+			rawURL:             "https://my-minio.example.com/bucket/key",
+			wantEndpoint:       "my-minio.example.com",
+			wantForcePathStyle: true,
+			wantDisableSSL:     false,
+		},
+		{
+			name:               "plain http host",
+			rawURL:             "http://my-minio.example.com/bucket/key",
+			wantEndpoint:       "my-minio.example.com",
+			wantForcePathStyle: true,
+			wantDisableSSL:     true,
+		},
+		{
+			name:               "anonymous",
+			rawURL:             "https://my-minio.example.com/bucket/key?anonymous=true",
+			wantEndpoint:       "my-minio.example.com",
+			wantForcePathStyle: true,
+			wantDisableSSL:     false,
+		},
+		{
+			name:               "explicit https endpoint",
+			rawURL:             "https://unused.example.com/bucket/key?endpoint=minio.internal:9000&s3_force_path_style=true",
+			wantEndpoint:       "minio.internal:9000",
+			wantForcePathStyle: true,
+			wantDisableSSL:     false,
+		},
+		{
+			name:               "explicit http endpoint",
+			rawURL:             "http://unused.example.com/bucket/key?endpoint=minio.internal:9000",
+			wantEndpoint:       "minio.internal:9000",
+			wantForcePathStyle: false,
+			wantDisableSSL:     true,
+		},
+	}
+
+	var g S3Getter
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.rawURL)
+			if err != nil {
+				t.Fatalf("url.Parse: %v", err)
+			}
+
+			parsed, err := g.parseUrl(u)
+			if err != nil {
+				t.Fatalf("parseUrl: %v", err)
+			}
+
+			conf := g.getAWSConfig(parsed)
+
+			if got := aws.StringValue(conf.Endpoint); got != tc.wantEndpoint {
+				t.Errorf("Endpoint = %q, want %q", got, tc.wantEndpoint)
+			}
+			if got := aws.BoolValue(conf.S3ForcePathStyle); got != tc.wantForcePathStyle {
+				t.Errorf("S3ForcePathStyle = %v, want %v", got, tc.wantForcePathStyle)
+			}
+			if got := aws.BoolValue(conf.DisableSSL); got != tc.wantDisableSSL {
+				t.Errorf("DisableSSL = %v, want %v", got, tc.wantDisableSSL)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+//go:build darwin
+// +build darwin
+
+package getter
+
+import "golang.org/x/sys/unix"
+
+// reflink attempts a copy-on-write clone of srcPath into dstPath using the
+// Darwin clonefile(2) syscall. On APFS this is O(1) and doesn't consume
+// extra disk until one of the files is later modified.
+func reflink(srcPath, dstPath string) error {
+	return unix.Clonefile(srcPath, dstPath, 0)
+}
+
+// reflinkFallback reports whether err indicates that the reflink fast path
+// isn't available and callers should fall back to a regular byte copy.
+func reflinkFallback(err error) bool {
+	switch err {
+	case unix.EXDEV, unix.ENOTSUP, unix.EINVAL, unix.ENOSYS:
+		return true
+	default:
+		return false
+	}
+}
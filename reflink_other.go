@@ -0,0 +1,22 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package getter
+
+import "errors"
+
+// errReflinkUnsupported is returned by reflink on platforms that don't
+// have a copy-on-write clone syscall wired up.
+var errReflinkUnsupported = errors.New("reflink: not supported on this platform")
+
+// reflink is a no-op on this platform; callers always fall back to a
+// regular byte copy.
+func reflink(srcPath, dstPath string) error {
+	return errReflinkUnsupported
+}
+
+// reflinkFallback reports whether err indicates that the reflink fast path
+// isn't available and callers should fall back to a regular byte copy.
+func reflinkFallback(err error) bool {
+	return err == errReflinkUnsupported
+}